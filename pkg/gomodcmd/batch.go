@@ -0,0 +1,310 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package gomodcmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// workDirName is the subdirectory of modDir that scratch per-package module copies used by
+// GetDAll/InstallAll are created under.
+const workDirName = ".work"
+
+// PackageSpec identifies a single package to resolve or install as part of a GetDAll/InstallAll
+// batch. ID must be unique within a batch; it keys the scratch work directory used to isolate
+// this package's `go get`/`go install` run as well as any error reported back for it.
+type PackageSpec struct {
+	ID       string
+	Packages []string
+}
+
+// MultiError aggregates the per-package errors from a GetDAll/InstallAll batch, keyed by the
+// PackageSpec.ID that failed.
+type MultiError struct {
+	Errors map[string]error
+}
+
+func (m *MultiError) Error() string {
+	ids := make([]string, 0, len(m.Errors))
+	for id := range m.Errors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	msgs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", id, m.Errors[id]))
+	}
+	return fmt.Sprintf("%d package(s) failed:\n%s", len(ids), strings.Join(msgs, "\n"))
+}
+
+// GetDAll runs 'go get -d' for each PackageSpec concurrently, each against its own scratch copy
+// of the module file so that the network round-trips it triggers don't serialize. The resolved
+// pins and sums each one records are merged back into the canonical go.mod/go.sum under modDir
+// once every package has finished. A single bad package does not prevent the others from
+// completing; their errors are aggregated into the returned MultiError.
+func (c *Runner) GetDAll(ctx context.Context, update GetUpdatePolicy, specs []PackageSpec) error {
+	return c.runAll(ctx, specs, func(dir, goCmd string, spec PackageSpec) error {
+		args := []string{"get", "-d"}
+		if c.insecure {
+			args = append(args, "-insecure")
+		}
+		if update != NoUpdatePolicy {
+			args = append(args, string(update))
+		}
+		_, err := c.exec(ctx, dir, goCmd, append(args, spec.Packages...)...)
+		return err
+	})
+}
+
+// InstallAll runs 'go install' for each PackageSpec concurrently; see GetDAll for the isolation
+// and error aggregation semantics.
+func (c *Runner) InstallAll(ctx context.Context, specs []PackageSpec) error {
+	return c.runAll(ctx, specs, func(dir, goCmd string, spec PackageSpec) error {
+		_, err := c.exec(ctx, dir, goCmd, append([]string{"install"}, spec.Packages...)...)
+		return err
+	})
+}
+
+func (c *Runner) runAll(ctx context.Context, specs []PackageSpec, do func(dir, goCmd string, spec PackageSpec) error) error {
+	goCmd, err := c.ensureToolchain(ctx)
+	if err != nil {
+		return errors.Wrap(err, "ensure toolchain")
+	}
+
+	q := newWorkQueue()
+	dirs := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		spec := spec
+		dir, err := c.scratchModDir(spec.ID)
+		if err != nil {
+			return errors.Wrapf(err, "prepare scratch dir for %s", spec.ID)
+		}
+		dirs[spec.ID] = dir
+		q.Add(spec.ID, func() error {
+			return do(dir, goCmd, spec)
+		})
+	}
+
+	results := q.Do(runtime.GOMAXPROCS(0))
+
+	// Merge sequentially, and in a deterministic (sorted-by-id) order, now that every worker
+	// has finished - so the canonical go.mod/go.sum never need a lock of their own, and the
+	// merged result doesn't depend on goroutine scheduling.
+	ids := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		ids = append(ids, spec.ID)
+	}
+	sort.Strings(ids)
+
+	var okDirs []string
+	for _, id := range ids {
+		if results[id] == nil {
+			okDirs = append(okDirs, dirs[id])
+		}
+	}
+
+	if err := c.mergeGoMod(okDirs); err != nil {
+		for _, id := range ids {
+			if results[id] == nil {
+				results[id] = errors.Wrap(err, "merge go.mod")
+			}
+		}
+	} else if err := c.mergeGoSum(okDirs); err != nil {
+		for _, id := range ids {
+			if results[id] == nil {
+				results[id] = errors.Wrap(err, "merge go.sum")
+			}
+		}
+	}
+
+	for _, dir := range dirs {
+		_ = os.RemoveAll(dir)
+	}
+
+	merr := &MultiError{}
+	for id, err := range results {
+		if err != nil {
+			if merr.Errors == nil {
+				merr.Errors = map[string]error{}
+			}
+			merr.Errors[id] = err
+		}
+	}
+	if len(merr.Errors) == 0 {
+		return nil
+	}
+	return merr
+}
+
+// scratchModDir creates afresh (removing any leftover one) and returns an isolated copy of c.modDir's go.mod/go.sum
+// under modDir/.work/<hash of id>, so that a package's `go get`/`go install` run can write to
+// its own module file without racing other packages in the same batch.
+func (c *Runner) scratchModDir(id string) (string, error) {
+	sum := sha256.Sum256([]byte(id))
+	dir := filepath.Join(c.modDir, workDirName, hex.EncodeToString(sum[:8]))
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	for _, f := range []string{"go.mod", "go.sum"} {
+		if err := copyFileIfExists(filepath.Join(c.modDir, f), filepath.Join(dir, f)); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// mergeGoMod folds the require directives that each scratch dir's go get resolved into the
+// canonical go.mod under c.modDir. dirs is walked in the (caller-sorted) order given, but the
+// result does not depend on that order: where two dirs resolved the same module path to
+// different versions, the higher one wins, performing the same max-version selection MVS would
+// if the dirs had shared a single module graph all along.
+func (c *Runner) mergeGoMod(dirs []string) error {
+	maxVersion := map[string]string{}
+	for _, dir := range dirs {
+		scratchPath := filepath.Join(dir, "go.mod")
+		scratchData, err := os.ReadFile(scratchPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return errors.Wrapf(err, "read %s", scratchPath)
+		}
+		scratchMF, err := modfile.Parse(scratchPath, scratchData, nil)
+		if err != nil {
+			return errors.Wrapf(err, "parse %s", scratchPath)
+		}
+
+		for _, req := range scratchMF.Require {
+			if cur, ok := maxVersion[req.Mod.Path]; !ok || semver.Compare(req.Mod.Version, cur) > 0 {
+				maxVersion[req.Mod.Path] = req.Mod.Version
+			}
+		}
+	}
+	if len(maxVersion) == 0 {
+		return nil
+	}
+
+	paths := make([]string, 0, len(maxVersion))
+	for path := range maxVersion {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	canonicalPath := filepath.Join(c.modDir, "go.mod")
+	canonicalData, err := os.ReadFile(canonicalPath)
+	if err != nil {
+		return errors.Wrapf(err, "read %s", canonicalPath)
+	}
+	canonicalMF, err := modfile.Parse(canonicalPath, canonicalData, nil)
+	if err != nil {
+		return errors.Wrapf(err, "parse %s", canonicalPath)
+	}
+
+	for _, path := range paths {
+		if err := canonicalMF.AddRequire(path, maxVersion[path]); err != nil {
+			return errors.Wrapf(err, "add require %s@%s", path, maxVersion[path])
+		}
+	}
+	canonicalMF.Cleanup()
+
+	out, err := canonicalMF.Format()
+	if err != nil {
+		return errors.Wrapf(err, "format %s", canonicalPath)
+	}
+	return os.WriteFile(canonicalPath, out, 0o644)
+}
+
+// mergeGoSum folds any go.sum lines produced across dirs into the canonical go.sum under
+// c.modDir, de-duplicating and keeping the result sorted - independent of dirs' order.
+func (c *Runner) mergeGoSum(dirs []string) error {
+	canonical, err := readLines(filepath.Join(c.modDir, "go.sum"))
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]struct{}, len(canonical))
+	merged := make([]string, 0, len(canonical))
+	for _, l := range canonical {
+		if _, ok := seen[l]; !ok {
+			seen[l] = struct{}{}
+			merged = append(merged, l)
+		}
+	}
+
+	changed := false
+	for _, dir := range dirs {
+		scratch, err := readLines(filepath.Join(dir, "go.sum"))
+		if err != nil {
+			return err
+		}
+		for _, l := range scratch {
+			if _, ok := seen[l]; !ok {
+				seen[l] = struct{}{}
+				merged = append(merged, l)
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	sort.Strings(merged)
+
+	return os.WriteFile(filepath.Join(c.modDir, "go.sum"), []byte(strings.Join(merged, "\n")+"\n"), 0o644)
+}
+
+func readLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []string
+	for _, l := range strings.Split(string(data), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+func copyFileIfExists(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
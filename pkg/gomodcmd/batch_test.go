@@ -0,0 +1,116 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package gomodcmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeGoSum(t *testing.T) {
+	modDir := t.TempDir()
+	canonical := "example.com/a v1.0.0 h1:aaa=\nexample.com/a v1.0.0/go.mod h1:bbb=\n"
+	if err := os.WriteFile(filepath.Join(modDir, "go.sum"), []byte(canonical), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	scratch := t.TempDir()
+	scratchSum := "example.com/a v1.0.0 h1:aaa=\nexample.com/b v1.2.0 h1:ccc=\n"
+	if err := os.WriteFile(filepath.Join(scratch, "go.sum"), []byte(scratchSum), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Runner{modDir: modDir}
+	if err := r.mergeGoSum([]string{scratch}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(modDir, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"example.com/a v1.0.0 h1:aaa=",
+		"example.com/a v1.0.0/go.mod h1:bbb=",
+		"example.com/b v1.2.0 h1:ccc=",
+	} {
+		if !strings.Contains(string(got), line) {
+			t.Errorf("merged go.sum missing line %q; got:\n%s", line, got)
+		}
+	}
+	if strings.Count(string(got), "example.com/a v1.0.0 h1:aaa=") != 1 {
+		t.Errorf("merged go.sum should de-duplicate the shared line; got:\n%s", got)
+	}
+}
+
+func TestMergeGoMod(t *testing.T) {
+	modDir := t.TempDir()
+	canonical := "module example.com/tool\n\ngo 1.16\n\nrequire example.com/a v1.0.0\n"
+	if err := os.WriteFile(filepath.Join(modDir, "go.mod"), []byte(canonical), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	scratch := t.TempDir()
+	scratchMod := "module example.com/tool\n\ngo 1.16\n\nrequire (\n\texample.com/a v1.1.0\n\texample.com/b v1.2.0\n)\n"
+	if err := os.WriteFile(filepath.Join(scratch, "go.mod"), []byte(scratchMod), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Runner{modDir: modDir}
+	if err := r.mergeGoMod([]string{scratch}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(modDir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "example.com/a v1.1.0") {
+		t.Errorf("merged go.mod should have bumped example.com/a to v1.1.0; got:\n%s", got)
+	}
+	if !strings.Contains(string(got), "example.com/b v1.2.0") {
+		t.Errorf("merged go.mod should have added example.com/b v1.2.0; got:\n%s", got)
+	}
+}
+
+func TestMergeGoModPicksMaxVersionAcrossDirs(t *testing.T) {
+	modDir := t.TempDir()
+	canonical := "module example.com/tool\n\ngo 1.16\n"
+	if err := os.WriteFile(filepath.Join(modDir, "go.mod"), []byte(canonical), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dirA := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dirA, "go.mod"), []byte("module example.com/tool\n\ngo 1.16\n\nrequire golang.org/x/tools v0.5.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dirB := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dirB, "go.mod"), []byte("module example.com/tool\n\ngo 1.16\n\nrequire golang.org/x/tools v0.9.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Runner{modDir: modDir}
+
+	// The merge result must not depend on which scratch dir is passed first.
+	for _, dirs := range [][]string{{dirA, dirB}, {dirB, dirA}} {
+		if err := os.WriteFile(filepath.Join(modDir, "go.mod"), []byte(canonical), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.mergeGoMod(dirs); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(modDir, "go.mod"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(got), "golang.org/x/tools v0.9.0") {
+			t.Errorf("merged go.mod should have picked the higher version v0.9.0 regardless of dir order %v; got:\n%s", dirs, got)
+		}
+		if strings.Contains(string(got), "v0.5.0") {
+			t.Errorf("merged go.mod should not contain the lower version v0.5.0; got:\n%s", got)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package gomodcmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ModuleError mirrors the "Error" field of `go list -m -json`'s output.
+type ModuleError struct {
+	Err string
+}
+
+// Module mirrors the fields `go help list` documents for `go list -m -json`, decoded straight
+// from the toolchain instead of scraped from its human-readable table output.
+type Module struct {
+	Path      string
+	Version   string       `json:",omitempty"`
+	Main      bool         `json:",omitempty"`
+	Indirect  bool         `json:",omitempty"`
+	Dir       string       `json:",omitempty"`
+	GoMod     string       `json:",omitempty"`
+	GoVersion string       `json:",omitempty"`
+	Replace   *Module      `json:",omitempty"`
+	Error     *ModuleError `json:",omitempty"`
+}
+
+// ListModule runs 'go list -m -json' with the given extra args (e.g. a module pattern, or
+// "-versions") in the process's current working directory, and decodes the resulting stream of
+// JSON objects into Modules. Unlike plain 'go list -m', this surfaces Replace, GoMod and
+// GoVersion - in particular GoMod, which with '-modfile' in use may live outside that directory
+// entirely. Note this intentionally runs in the caller's working directory rather than modDir:
+// at the point it's used for current-module detection, modDir may not have a go.mod yet.
+func (c *Runner) ListModule(ctx context.Context, args ...string) ([]Module, error) {
+	out, err := c.execGo(ctx, append([]string{"list", "-m", "-json"}, args...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "go list -m -json")
+	}
+
+	var mods []Module
+	dec := json.NewDecoder(strings.NewReader(out))
+	for dec.More() {
+		var m Module
+		if err := dec.Decode(&m); err != nil {
+			return nil, errors.Wrap(err, "decode go list -m -json output")
+		}
+		mods = append(mods, m)
+	}
+	return mods, nil
+}
+
+// currentModule picks the Module describing the caller's current working directory out of
+// mods, as returned by ListModule(ctx) with no extra args. In single-module mode that's simply
+// the lone result; in workspace mode (a go.work in scope), 'go list -m' without arguments
+// returns every module in the workspace, so this disambiguates by matching Dir against the
+// working directory.
+func currentModule(mods []Module) (Module, error) {
+	var main []Module
+	for _, m := range mods {
+		if m.Main {
+			main = append(main, m)
+		}
+	}
+
+	switch len(main) {
+	case 0:
+		return Module{}, errors.New("'go list -m -json' returned no main module")
+	case 1:
+		return main[0], nil
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return Module{}, errors.Wrap(err, "getwd")
+	}
+	for _, m := range main {
+		if m.Dir == wd {
+			return m, nil
+		}
+	}
+	return Module{}, errors.Errorf("found %d main modules (workspace mode); run from one of their directories to disambiguate", len(main))
+}
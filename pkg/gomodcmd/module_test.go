@@ -0,0 +1,54 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package gomodcmd
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCurrentModuleSingle(t *testing.T) {
+	mods := []Module{{Path: "example.com/a", Main: true}}
+	got, err := currentModule(mods)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Path != "example.com/a" {
+		t.Errorf("got %q; want example.com/a", got.Path)
+	}
+}
+
+func TestCurrentModuleWorkspaceDisambiguatesByDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mods := []Module{
+		{Path: "example.com/a", Main: true, Dir: "/somewhere/else"},
+		{Path: "example.com/b", Main: true, Dir: wd},
+	}
+	got, err := currentModule(mods)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Path != "example.com/b" {
+		t.Errorf("got %q; want example.com/b", got.Path)
+	}
+}
+
+func TestCurrentModuleWorkspaceAmbiguous(t *testing.T) {
+	mods := []Module{
+		{Path: "example.com/a", Main: true, Dir: "/somewhere/else"},
+		{Path: "example.com/b", Main: true, Dir: "/somewhere/else/too"},
+	}
+	if _, err := currentModule(mods); err == nil {
+		t.Error("expected an error when no main module matches the working directory")
+	}
+}
+
+func TestCurrentModuleNoMain(t *testing.T) {
+	if _, err := currentModule(nil); err == nil {
+		t.Error("expected an error for no main modules")
+	}
+}
@@ -20,24 +20,45 @@ type Runner struct {
 	modDir   string
 	insecure bool
 
+	// toolchain pins the Go toolchain (e.g. "go1.21.4") that commands are run
+	// with, overriding whatever the managed go.mod's go/toolchain directives
+	// would otherwise select. Set via WithToolchain.
+	toolchain string
+
+	// workFile, if set, points at a go.work file that every exec'd command is pointed at via
+	// GOWORK, so that MVS runs once across the whole workspace instead of once per modDir. Set
+	// by NewWorkspaceRunner.
+	workFile string
+
 	verbose bool
 }
 
-// NewRunner checks Go version compatibility and initialize new go.mod in the modDir if not yet present, then returns Runner.
-func NewRunner(ctx context.Context, insecure bool, modDir string, goCmd string) (*Runner, error) {
+// RunnerOption configures optional Runner behaviour.
+type RunnerOption func(*Runner)
+
+// WithToolchain pins the Runner to a specific Go toolchain (e.g. "go1.21.4"),
+// regardless of the go/toolchain directives found in the managed go.mod.
+func WithToolchain(name string) RunnerOption {
+	return func(r *Runner) {
+		r.toolchain = name
+	}
+}
+
+// NewRunner initializes new go.mod in the modDir if not yet present, then returns Runner. The
+// Go toolchain used to run commands against modDir is selected per invocation based on the
+// go/toolchain directives of its go.mod; see ensureToolchain and WithToolchain.
+func NewRunner(ctx context.Context, insecure bool, modDir string, goCmd string, opts ...RunnerOption) (*Runner, error) {
 	r := &Runner{
 		goCmd:    goCmd,
 		modDir:   modDir,
 		insecure: insecure,
 	}
-
-	ver, err := r.execGo(ctx, "version")
-	if err != nil {
-		return nil, errors.Wrap(err, "exec go to detect the version")
+	for _, opt := range opts {
+		opt(r)
 	}
 
-	if !strings.HasPrefix(ver, "go version go1.14.") {
-		return nil, errors.Errorf("found unsupported go version: %v. Requires go1.14.x", ver)
+	if _, err := r.execGo(ctx, "version"); err != nil {
+		return nil, errors.Wrap(err, "exec go to detect the version")
 	}
 
 	if err := os.MkdirAll(modDir, os.ModePerm); err != nil {
@@ -48,14 +69,21 @@ func NewRunner(ctx context.Context, insecure bool, modDir string, goCmd string)
 		if !os.IsNotExist(err) {
 			return nil, errors.Wrapf(err, "stat module file %s", filepath.Join(r.modDir, "go.mod"))
 		}
-		currMod, err := r.execGo(ctx, "list", "-m")
+		mods, err := r.ListModule(ctx)
+		if err != nil {
+			return nil, err
+		}
+		curr, err := currentModule(mods)
 		if err != nil {
 			return nil, err
 		}
+		if curr.Error != nil {
+			return nil, errors.Errorf("resolve current module: %s", curr.Error.Err)
+		}
 
 		// TODO(bwplotka): Check if currMod is not gobin..
 
-		if _, err := r.execGoInModDir(ctx, "mod", "init", filepath.Join(currMod, r.modDir)); err != nil {
+		if _, err := r.execGoInModDir(ctx, "mod", "init", filepath.Join(curr.Path, r.modDir)); err != nil {
 			return nil, err
 		}
 	}
@@ -67,12 +95,19 @@ func (c *Runner) execGo(ctx context.Context, args ...string) (string, error) {
 }
 
 func (c *Runner) execGoInModDir(ctx context.Context, args ...string) (string, error) {
-	return c.exec(ctx, c.modDir, c.goCmd, args...)
+	goCmd, err := c.ensureToolchain(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "ensure toolchain")
+	}
+	return c.exec(ctx, c.modDir, goCmd, args...)
 }
 
 func (c *Runner) exec(ctx context.Context, cd string, command string, args ...string) (string, error) {
 	cmd := exec.CommandContext(ctx, command, args...)
 	cmd.Dir = filepath.Join(cmd.Dir, cd)
+	if c.workFile != "" {
+		cmd.Env = append(os.Environ(), "GOWORK="+c.workFile)
+	}
 	var b bytes.Buffer
 	cmd.Stdout = &b
 	cmd.Stderr = &b
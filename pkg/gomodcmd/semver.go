@@ -0,0 +1,207 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package gomodcmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/apidiff"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/tools/go/packages"
+)
+
+// CompatLevel classifies the API delta between two versions of a package as needing a patch,
+// minor (additive) or major (breaking) version bump, the same levels gorelease reports.
+type CompatLevel int
+
+const (
+	CompatNone CompatLevel = iota
+	CompatPatch
+	CompatMinor
+	CompatMajor
+)
+
+func (l CompatLevel) String() string {
+	switch l {
+	case CompatPatch:
+		return "patch-compatible"
+	case CompatMinor:
+		return "minor-compatible (additive)"
+	case CompatMajor:
+		return "incompatible (breaking)"
+	default:
+		return "no change"
+	}
+}
+
+// UpgradeReport describes the exported API delta CheckUpgrade found between two module versions.
+type UpgradeReport struct {
+	Package string
+	From    module.Version
+	To      module.Version
+
+	Level CompatLevel
+
+	Added   []string
+	Removed []string
+	Changed []string
+
+	// RecommendedMin is the smallest version satisfying semantic import versioning rules for
+	// the delta found, e.g. a bump to the next major if Level is CompatMajor.
+	RecommendedMin string
+}
+
+// Breaking reports whether to violates semver given the exported API delta found, e.g. a
+// v1.2.3->v1.3.0 bump that removes an exported symbol.
+func (r *UpgradeReport) Breaking() bool {
+	return r.Level == CompatMajor && !isMajorBump(r.From.Version, r.To.Version)
+}
+
+// CheckUpgrade downloads both pkg@from.Version and pkg@to.Version into the module cache, loads
+// each in module mode, and diffs their exported API with apidiff - the same analysis technique
+// gorelease uses. Callers (e.g. the CLI, before GetD with an UpdatePolicy rewrites a pin) should
+// treat a Breaking report as an error unless the user passed something like --force.
+func (c *Runner) CheckUpgrade(ctx context.Context, pkg string, from, to module.Version) (*UpgradeReport, error) {
+	goCmd, err := c.ensureToolchain(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "ensure toolchain")
+	}
+
+	fromDir, err := c.downloadModule(ctx, goCmd, pkg, from.Version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "download %s@%s", pkg, from.Version)
+	}
+	toDir, err := c.downloadModule(ctx, goCmd, pkg, to.Version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "download %s@%s", pkg, to.Version)
+	}
+
+	fromPkg, err := loadPackageAPI(ctx, goCmd, fromDir, pkg)
+	if err != nil {
+		return nil, errors.Wrapf(err, "load %s@%s", pkg, from.Version)
+	}
+	toPkg, err := loadPackageAPI(ctx, goCmd, toDir, pkg)
+	if err != nil {
+		return nil, errors.Wrapf(err, "load %s@%s", pkg, to.Version)
+	}
+
+	report := apidiff.Changes(fromPkg.Types, toPkg.Types)
+
+	r := &UpgradeReport{
+		Package: pkg,
+		From:    from,
+		To:      to,
+	}
+	for _, ch := range report.Changes {
+		switch {
+		case ch.Compatible && strings.Contains(ch.Message, "added"):
+			// apidiff reports purely additive changes (new exported symbols) as compatible
+			// messages containing "added"; bucket those separately from compatible changes to
+			// existing symbols so the report's added/removed/changed breakdown is meaningful.
+			r.Added = append(r.Added, ch.Message)
+			if r.Level < CompatMinor {
+				r.Level = CompatMinor
+			}
+		case ch.Compatible:
+			r.Changed = append(r.Changed, ch.Message)
+			if r.Level < CompatMinor {
+				r.Level = CompatMinor
+			}
+		case strings.Contains(ch.Message, "removed"):
+			r.Removed = append(r.Removed, ch.Message)
+			r.Level = CompatMajor
+		default:
+			r.Changed = append(r.Changed, ch.Message)
+			r.Level = CompatMajor
+		}
+	}
+	if r.Level == CompatNone {
+		r.Level = CompatPatch
+	}
+	r.RecommendedMin = recommendedMinVersion(from.Version, to.Version, r.Level)
+
+	if r.Breaking() {
+		return r, errors.Errorf("%s: upgrading %s -> %s is a breaking change (recommend >= %s); rerun with --force to proceed anyway", pkg, from.Version, to.Version, r.RecommendedMin)
+	}
+	return r, nil
+}
+
+// downloadModule runs 'go mod download -json' for module@version and returns its on-disk Dir.
+func (c *Runner) downloadModule(ctx context.Context, goCmd, mod, version string) (string, error) {
+	out, err := c.exec(ctx, c.modDir, goCmd, "mod", "download", "-json", fmt.Sprintf("%s@%s", mod, version))
+	if err != nil {
+		return "", err
+	}
+	var info struct {
+		Dir   string
+		Error string
+	}
+	if err := json.Unmarshal([]byte(out), &info); err != nil {
+		return "", errors.Wrap(err, "decode go mod download output")
+	}
+	if info.Error != "" {
+		return "", errors.New(info.Error)
+	}
+	return info.Dir, nil
+}
+
+// loadPackageAPI loads pkg from dir in module mode, for use as one side of an apidiff comparison.
+func loadPackageAPI(ctx context.Context, goCmd, dir, pkg string) (*packages.Package, error) {
+	cfg := &packages.Config{
+		Context: ctx,
+		Mode:    packages.NeedName | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedDeps,
+		Dir:     dir,
+		Tests:   false,
+		Env:     envWithGoCmd(goCmd),
+	}
+	pkgs, err := packages.Load(cfg, pkg)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) == 0 {
+		return nil, errors.Errorf("no packages found for %s in %s", pkg, dir)
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, errors.Errorf("errors loading %s in %s", pkg, dir)
+	}
+	return pkgs[0], nil
+}
+
+// envWithGoCmd puts the directory containing goCmd at the front of PATH, so that
+// golang.org/x/tools/go/packages - which always invokes a binary named "go" - resolves to the
+// toolchain the Runner selected rather than whatever "go" happens to be on the caller's PATH.
+func envWithGoCmd(goCmd string) []string {
+	return append(os.Environ(), "PATH="+filepath.Dir(goCmd)+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// isMajorBump reports whether to is in the next (or a later) major version line relative to
+// from, i.e. whether bumping to it is expected to break compatibility under semantic import
+// versioning.
+func isMajorBump(from, to string) bool {
+	return semver.Major(from) != semver.Major(to)
+}
+
+// recommendedMinVersion returns the smallest version satisfying semantic import versioning for
+// the given compat level. A breaking (CompatMajor) delta requires bumping the module's major
+// version line; anything else is satisfied by the version already proposed.
+func recommendedMinVersion(from, to string, level CompatLevel) string {
+	if level != CompatMajor {
+		return to
+	}
+	return nextMajor(from)
+}
+
+func nextMajor(v string) string {
+	major := strings.TrimPrefix(semver.Major(v), "v")
+	n := 0
+	fmt.Sscanf(major, "%d", &n)
+	return fmt.Sprintf("v%d.0.0", n+1)
+}
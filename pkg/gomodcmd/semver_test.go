@@ -0,0 +1,46 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package gomodcmd
+
+import "testing"
+
+func TestNextMajor(t *testing.T) {
+	for _, tc := range []struct {
+		v    string
+		want string
+	}{
+		{"v1.2.3", "v2.0.0"},
+		{"v0.5.0", "v1.0.0"},
+		{"v2.9.9", "v3.0.0"},
+	} {
+		if got := nextMajor(tc.v); got != tc.want {
+			t.Errorf("nextMajor(%s) = %s; want %s", tc.v, got, tc.want)
+		}
+	}
+}
+
+func TestRecommendedMinVersion(t *testing.T) {
+	for _, tc := range []struct {
+		from, to string
+		level    CompatLevel
+		want     string
+	}{
+		{"v1.2.3", "v1.2.4", CompatPatch, "v1.2.4"},
+		{"v1.2.3", "v1.3.0", CompatMinor, "v1.3.0"},
+		{"v1.2.3", "v1.3.0", CompatMajor, "v2.0.0"},
+	} {
+		if got := recommendedMinVersion(tc.from, tc.to, tc.level); got != tc.want {
+			t.Errorf("recommendedMinVersion(%s, %s, %v) = %s; want %s", tc.from, tc.to, tc.level, got, tc.want)
+		}
+	}
+}
+
+func TestIsMajorBump(t *testing.T) {
+	if isMajorBump("v1.2.3", "v1.3.0") {
+		t.Error("v1.2.3 -> v1.3.0 should not be a major bump")
+	}
+	if !isMajorBump("v1.2.3", "v2.0.0") {
+		t.Error("v1.2.3 -> v2.0.0 should be a major bump")
+	}
+}
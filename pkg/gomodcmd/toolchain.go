@@ -0,0 +1,275 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package gomodcmd
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// toolchainsDir is the name of the directory, relative to a Runner's modDir, that cached
+// toolchains downloaded by ensureToolchain are extracted into.
+const toolchainsDir = ".toolchains"
+
+// ensureToolchain returns the path to the go binary that commands against c.modDir should be
+// run with. If the Runner was pinned with WithToolchain, that toolchain is used. Otherwise, the
+// go/toolchain directives of c.modDir's go.mod (if any) are compared against the toolchain
+// reported by `go version`; if the local toolchain is older than what the go.mod requires, the
+// requested toolchain is downloaded and cached under modDir/.toolchains, mirroring the
+// auto-switching behaviour of `go` itself.
+func (c *Runner) ensureToolchain(ctx context.Context) (string, error) {
+	want := c.toolchain
+	if want == "" {
+		name, err := c.requiredToolchain(ctx)
+		if err != nil {
+			return "", err
+		}
+		want = name
+	}
+	if want == "" {
+		return c.goCmd, nil
+	}
+
+	have, err := c.toolchainVersion(ctx, c.goCmd)
+	if err != nil {
+		return "", errors.Wrap(err, "detect local go version")
+	}
+	if have == want || (c.toolchain == "" && compareGoVersions(have, want) >= 0) {
+		return c.goCmd, nil
+	}
+
+	return c.downloadToolchain(ctx, want)
+}
+
+// requiredToolchain inspects c.modDir's go.mod, if present, and returns the toolchain name
+// (e.g. "go1.21.4") it requires, derived from its toolchain directive or, failing that, its go
+// directive. It returns "" if there is no go.mod yet or it carries no go directive.
+func (c *Runner) requiredToolchain(ctx context.Context) (string, error) {
+	path := filepath.Join(c.modDir, "go.mod")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "read %s", path)
+	}
+
+	mf, err := modfile.Parse(path, data, nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse %s", path)
+	}
+
+	if mf.Toolchain != nil && mf.Toolchain.Name != "" {
+		return mf.Toolchain.Name, nil
+	}
+	if mf.Go != nil && mf.Go.Version != "" {
+		return "go" + mf.Go.Version, nil
+	}
+	return "", nil
+}
+
+// toolchainVersion returns the "goX.Y.Z" version reported by `<goCmd> version`.
+func (c *Runner) toolchainVersion(ctx context.Context, goCmd string) (string, error) {
+	out, err := c.exec(ctx, "", goCmd, "version")
+	if err != nil {
+		return "", err
+	}
+	return parseGoVersionOutput(out)
+}
+
+// parseGoVersionOutput extracts the "goX.Y.Z" token out of `go version`'s output, e.g.
+// "go version go1.21.6 linux/amd64". Note the first field is the literal word "go", not a
+// version, so this must match on the "go1."-prefixed token rather than just skipping "go".
+func parseGoVersionOutput(out string) (string, error) {
+	for _, f := range strings.Fields(out) {
+		if strings.HasPrefix(f, "go1.") {
+			return f, nil
+		}
+	}
+	return "", errors.Errorf("unexpected 'go version' output: %s", out)
+}
+
+// downloadToolchain fetches the named toolchain (e.g. "go1.21.4") from the Go module proxy,
+// the same GOTOOLCHAIN mechanism the go command itself uses, caching it under
+// modDir/.toolchains/<name> and returning the path to its go binary.
+func (c *Runner) downloadToolchain(ctx context.Context, name string) (string, error) {
+	dir := filepath.Join(c.modDir, toolchainsDir, name)
+	bin := filepath.Join(dir, "bin", "go")
+	if _, err := os.Stat(bin); err == nil {
+		return bin, nil
+	}
+
+	if err := os.MkdirAll(filepath.Join(c.modDir, toolchainsDir), os.ModePerm); err != nil {
+		return "", errors.Wrapf(err, "create toolchains dir %s", filepath.Join(c.modDir, toolchainsDir))
+	}
+
+	proxy := os.Getenv("GOPROXY")
+	if proxy == "" {
+		proxy = "https://proxy.golang.org"
+	}
+	// Toolchains are distributed as the golang.org/toolchain module, versioned per GOOS/GOARCH,
+	// following the scheme the go command itself uses for GOTOOLCHAIN.
+	version := fmt.Sprintf("v0.0.1-%s.%s-%s", name, runtime.GOOS, runtime.GOARCH)
+	url := fmt.Sprintf("%s/golang.org/toolchain/@v/%s.zip", strings.TrimRight(proxy, "/"), version)
+
+	tmp := dir + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(tmp, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	if err := fetchAndExtract(ctx, url, tmp); err != nil {
+		return "", errors.Wrapf(err, "download toolchain %s", name)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return "", errors.Wrapf(err, "install toolchain %s", name)
+	}
+	return bin, nil
+}
+
+// fetchAndExtract downloads the zip archive at url and extracts it into dir, stripping the
+// module zip's top-level "<module>@<version>/" directory and restoring the executable bit on
+// everything under bin/. It is kept as a narrow seam so tests can stub it out without a network
+// dependency.
+var fetchAndExtract = func(ctx context.Context, url, dir string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return errors.Errorf("GET %s: %s: %s", url, resp.Status, string(b))
+	}
+
+	tmpZip, err := os.CreateTemp("", "bingo-toolchain-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpZip.Name())
+	defer tmpZip.Close()
+
+	if _, err := io.Copy(tmpZip, resp.Body); err != nil {
+		return errors.Wrap(err, "save toolchain archive")
+	}
+
+	return extractZip(tmpZip.Name(), dir)
+}
+
+// extractZip unpacks the module zip at zipPath into dir, stripping its top-level
+// "<module>@<version>/" directory (as produced by the Go module proxy), and marking anything
+// under bin/ executable.
+func extractZip(zipPath, dir string) error {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return errors.Wrap(err, "open toolchain archive")
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		rel := stripZipModulePrefix(f.Name)
+		if rel == "" {
+			continue
+		}
+		target := filepath.Join(dir, rel)
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		if err := extractZipFile(f, target); err != nil {
+			return errors.Wrapf(err, "extract %s", f.Name)
+		}
+	}
+	return nil
+}
+
+func extractZipFile(f *zip.File, target string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	mode := f.Mode()
+	if strings.HasPrefix(filepath.ToSlash(stripZipModulePrefix(f.Name)), "bin/") {
+		mode |= 0o111
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, rc)
+	return err
+}
+
+// stripZipModulePrefix drops the leading "<module>@<version>/" path component Go module zips
+// are packed with, returning "" for the top-level directory entry itself. The module path
+// itself (e.g. "golang.org/toolchain") may contain slashes, so this strips through the first
+// "/" that follows the "@version" marker rather than the first "/" in the name.
+func stripZipModulePrefix(name string) string {
+	at := strings.Index(name, "@")
+	if at < 0 {
+		return ""
+	}
+	i := strings.Index(name[at:], "/")
+	if i < 0 {
+		return ""
+	}
+	rest := name[at+i+1:]
+	if rest == "" {
+		return ""
+	}
+	return rest
+}
+
+// compareGoVersions compares two "goX.Y.Z"-style version strings, returning -1, 0 or 1 as a is
+// less than, equal to, or greater than b. Missing components are treated as 0.
+func compareGoVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "go"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "go"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var na, nb int
+		if i < len(pa) {
+			na, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			nb, _ = strconv.Atoi(pb[i])
+		}
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package gomodcmd
+
+import "testing"
+
+func TestCompareGoVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"go1.14.15", "go1.14.15", 0},
+		{"go1.14", "go1.21.4", -1},
+		{"go1.21.4", "go1.14", 1},
+		{"go1.21.4", "go1.21.10", -1},
+		{"go1.21.10", "go1.21.4", 1},
+	} {
+		if got := compareGoVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareGoVersions(%s, %s) = %d; want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestParseGoVersionOutput(t *testing.T) {
+	for _, tc := range []struct {
+		out     string
+		want    string
+		wantErr bool
+	}{
+		{"go version go1.21.6 linux/amd64", "go1.21.6", false},
+		{"go version go1.14.15 darwin/arm64", "go1.14.15", false},
+		{"not go version output at all", "", true},
+	} {
+		got, err := parseGoVersionOutput(tc.out)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseGoVersionOutput(%q): expected an error, got %q", tc.out, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseGoVersionOutput(%q): unexpected error: %v", tc.out, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("parseGoVersionOutput(%q) = %q; want %q", tc.out, got, tc.want)
+		}
+	}
+}
+
+func TestStripZipModulePrefix(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		want string
+	}{
+		{"golang.org/toolchain@v0.0.1-go1.21.4.linux-amd64/", ""},
+		{"golang.org/toolchain@v0.0.1-go1.21.4.linux-amd64/bin/go", "bin/go"},
+		{"golang.org/toolchain@v0.0.1-go1.21.4.linux-amd64/pkg/tool/linux_amd64/compile", "pkg/tool/linux_amd64/compile"},
+	} {
+		if got := stripZipModulePrefix(tc.name); got != tc.want {
+			t.Errorf("stripZipModulePrefix(%s) = %q; want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package gomodcmd
+
+import "sync"
+
+// workItem is a single unit of work submitted to a workQueue, keyed by id.
+type workItem struct {
+	id string
+	fn func() error
+}
+
+// workQueue runs a batch of functions across a bounded pool of goroutines, modeled on cmd/go's
+// par.Work, and collects one error per item keyed by the id it was added with.
+type workQueue struct {
+	items []workItem
+}
+
+func newWorkQueue() *workQueue {
+	return &workQueue{}
+}
+
+// Add registers fn to run as part of the next Do call, under the given id.
+func (q *workQueue) Add(id string, fn func() error) {
+	q.items = append(q.items, workItem{id: id, fn: fn})
+}
+
+// Do runs all added items across up to n goroutines and returns their errors keyed by id. It
+// blocks until every item has completed. n is clamped to [1, len(items)].
+func (q *workQueue) Do(n int) map[string]error {
+	results := make(map[string]error, len(q.items))
+	if len(q.items) == 0 {
+		return results
+	}
+	if n <= 0 {
+		n = 1
+	}
+	if n > len(q.items) {
+		n = len(q.items)
+	}
+
+	var mu sync.Mutex
+	items := make(chan workItem)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range items {
+				err := it.fn()
+				mu.Lock()
+				results[it.id] = err
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, it := range q.items {
+		items <- it
+	}
+	close(items)
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,58 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package gomodcmd
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestWorkQueueDo(t *testing.T) {
+	q := newWorkQueue()
+	var running, maxRunning int32
+
+	const n = 8
+	for i := 0; i < n; i++ {
+		i := i
+		q.Add(string(rune('a'+i)), func() error {
+			cur := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if cur <= max || atomic.CompareAndSwapInt32(&maxRunning, max, cur) {
+					break
+				}
+			}
+			if i == 3 {
+				return errors.New("boom")
+			}
+			return nil
+		})
+	}
+
+	results := q.Do(4)
+	if len(results) != n {
+		t.Fatalf("got %d results; want %d", len(results), n)
+	}
+	if results["d"] == nil {
+		t.Error("expected item \"d\" to have failed")
+	}
+	for id, err := range results {
+		if id != "d" && err != nil {
+			t.Errorf("item %q: unexpected error: %v", id, err)
+		}
+	}
+	if maxRunning > 4 {
+		t.Errorf("workQueue.Do(4) ran %d items concurrently; want <= 4", maxRunning)
+	}
+}
+
+func TestWorkQueueDoEmpty(t *testing.T) {
+	results := newWorkQueue().Do(4)
+	if len(results) != 0 {
+		t.Errorf("got %d results for an empty queue; want 0", len(results))
+	}
+}
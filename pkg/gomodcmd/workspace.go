@@ -0,0 +1,101 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package gomodcmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// NewWorkspaceRunner initializes (if not yet present) a go.work at modDir/go.work and returns a
+// Runner backed by it. Unlike NewRunner, where every pinned tool gets its own go.mod and thus
+// its own independently-resolved module graph, tools registered against this Runner via UseTool
+// share a single `go.work`, so MVS over any common dependency (e.g. golang.org/x/tools) runs
+// once for all of them instead of once per tool.
+func NewWorkspaceRunner(ctx context.Context, modDir string, goCmd string, opts ...RunnerOption) (*Runner, error) {
+	r := &Runner{
+		goCmd:    goCmd,
+		modDir:   modDir,
+		workFile: filepath.Join(modDir, "go.work"),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if _, err := r.execGo(ctx, "version"); err != nil {
+		return nil, errors.Wrap(err, "exec go to detect the version")
+	}
+
+	if err := os.MkdirAll(modDir, os.ModePerm); err != nil {
+		return nil, errors.Wrapf(err, "create moddir %s", modDir)
+	}
+
+	if _, err := os.Stat(r.workFile); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "stat workspace file %s", r.workFile)
+		}
+		if _, err := r.execGoInModDir(ctx, "work", "init"); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// UseTool registers name as a module living at modDir/name, initializing its go.mod if it
+// doesn't exist yet and adding it to the workspace's `use` directives via `go work use`. It
+// returns a Runner scoped to that tool's module, sharing this workspace's go.work - so GetD,
+// Install and ModTidy called against it resolve against the shared module graph.
+func (c *Runner) UseTool(ctx context.Context, name string) (*Runner, error) {
+	if c.workFile == "" {
+		return nil, errors.New("UseTool is only supported for workspace-backed Runners created via NewWorkspaceRunner")
+	}
+
+	tool := &Runner{
+		goCmd:     c.goCmd,
+		modDir:    filepath.Join(c.modDir, name),
+		insecure:  c.insecure,
+		toolchain: c.toolchain,
+		workFile:  c.workFile,
+		verbose:   c.verbose,
+	}
+
+	if err := os.MkdirAll(tool.modDir, os.ModePerm); err != nil {
+		return nil, errors.Wrapf(err, "create tool dir %s", tool.modDir)
+	}
+
+	if _, err := os.Stat(filepath.Join(tool.modDir, "go.mod")); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "stat module file %s", filepath.Join(tool.modDir, "go.mod"))
+		}
+		mods, err := c.ListModule(ctx)
+		if err != nil {
+			return nil, err
+		}
+		curr, err := currentModule(mods)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tool.execGoInModDir(ctx, "mod", "init", filepath.Join(curr.Path, tool.modDir)); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := c.execGoInModDir(ctx, "work", "use", "./"+name); err != nil {
+		return nil, errors.Wrapf(err, "add %s to workspace", name)
+	}
+	return tool, nil
+}
+
+// Sync wraps `go work sync`, propagating the versions selected by the workspace's shared module
+// graph back down into each used tool's own go.mod/go.sum.
+func (c *Runner) Sync(ctx context.Context) error {
+	if c.workFile == "" {
+		return errors.New("Sync is only supported for workspace-backed Runners created via NewWorkspaceRunner")
+	}
+	_, err := c.execGoInModDir(ctx, "work", "sync")
+	return err
+}